@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Checkpoint is the on-disk, resumable record of a single scrape's
+// progress: which query it was run for, how far the "Next" page chain got,
+// and which results have already been written so a restarted run doesn't
+// duplicate them.
+type Checkpoint struct {
+	Query        string          `json:"query"`
+	Lang         string          `json:"lang"`
+	Sdt          string          `json:"sdt"`
+	CurrentPage  int             `json:"current_page"`
+	SeenResults  map[string]bool `json:"seen_results"`
+	TotalResults int             `json:"total_results"`
+	OutputPath   string          `json:"output_path"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// NewCheckpoint creates a fresh, empty checkpoint for query/lang/sdt that
+// will be written to path.
+func NewCheckpoint(path, query, lang, sdt string) *Checkpoint {
+	return &Checkpoint{
+		Query:       query,
+		Lang:        lang,
+		Sdt:         sdt,
+		SeenResults: make(map[string]bool),
+		path:        path,
+	}
+}
+
+// LoadCheckpoint reads a checkpoint from path. A missing file is not an
+// error: it simply returns a nil checkpoint, meaning "start from scratch".
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	if cp.SeenResults == nil {
+		cp.SeenResults = make(map[string]bool)
+	}
+	cp.path = path
+	return &cp, nil
+}
+
+// Matches reports whether the checkpoint was produced by the same query
+// parameters as the current invocation; a checkpoint for a different query
+// must not be resumed from.
+func (c *Checkpoint) Matches(query, lang, sdt string) bool {
+	return c.Query == query && c.Lang == lang && c.Sdt == sdt
+}
+
+// SetOutputPath records the sink file a fresh checkpoint's results are
+// being written to, so a resumed run reopens that same file (in append
+// mode) instead of starting a new, timestamped one that the seen-results
+// skip below would leave missing every row written before the restart.
+func (c *Checkpoint) SetOutputPath(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.OutputPath = path
+}
+
+// MarkResultSeen records a scraped result's hash and reports whether it is
+// new. Callers should skip writing the result to the sink when this
+// returns false, since it means a prior run (before a restart or ban)
+// already wrote it to OutputPath.
+func (c *Checkpoint) MarkResultSeen(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.SeenResults[hash] {
+		return false
+	}
+	c.SeenResults[hash] = true
+	c.TotalResults++
+	return true
+}
+
+// AdvancePage raises CurrentPage to page if page is further along than
+// what was previously recorded.
+func (c *Checkpoint) AdvancePage(page int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if page > c.CurrentPage {
+		c.CurrentPage = page
+	}
+}
+
+// Save writes the checkpoint to its path as indented JSON. It is safe to
+// call repeatedly over the course of a run; this is what lets a long crawl
+// survive a rate-limit ban or process restart.
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0600)
+}
+
+// resultHash identifies a scraped record for checkpoint dedup purposes,
+// independent of the VisitedSet URL hashes used to dedup the crawl
+// frontier itself. fields picks out which of record's values make up the
+// identity of a result (the active RuleSet's DedupFields) so dedup still
+// works for rule sets whose fields aren't named "Link" and "Title".
+func resultHash(record map[string]string, fields []string) string {
+	var key strings.Builder
+	for _, f := range fields {
+		key.WriteString(record[f])
+		key.WriteByte('|')
+	}
+	sum := sha1.Sum([]byte(key.String()))
+	return hex.EncodeToString(sum[:])
+}
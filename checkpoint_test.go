@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointMatches(t *testing.T) {
+	cp := NewCheckpoint("state.json", "cats", "en", "0,5")
+
+	if !cp.Matches("cats", "en", "0,5") {
+		t.Errorf("Matches should be true for the same query/lang/sdt")
+	}
+	if cp.Matches("dogs", "en", "0,5") {
+		t.Errorf("Matches should be false for a different query")
+	}
+}
+
+func TestCheckpointMarkResultSeen(t *testing.T) {
+	cp := NewCheckpoint("state.json", "cats", "en", "0,5")
+
+	if !cp.MarkResultSeen("hash-a") {
+		t.Errorf("first MarkResultSeen of a hash should report true")
+	}
+	if cp.MarkResultSeen("hash-a") {
+		t.Errorf("second MarkResultSeen of the same hash should report false")
+	}
+	if cp.TotalResults != 1 {
+		t.Errorf("TotalResults = %d, want 1", cp.TotalResults)
+	}
+}
+
+func TestCheckpointAdvancePageOnlyMovesForward(t *testing.T) {
+	cp := NewCheckpoint("state.json", "cats", "en", "0,5")
+
+	cp.AdvancePage(3)
+	cp.AdvancePage(1)
+	if cp.CurrentPage != 3 {
+		t.Errorf("CurrentPage = %d, want 3 (AdvancePage must not move backwards)", cp.CurrentPage)
+	}
+}
+
+func TestCheckpointSaveAndLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	cp := NewCheckpoint(path, "cats", "en", "0,5")
+	cp.AdvancePage(2)
+	cp.MarkResultSeen("hash-a")
+	cp.SetOutputPath(filepath.Join(dir, "scrape-cats.csv"))
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	if loaded == nil {
+		t.Fatalf("LoadCheckpoint returned nil for an existing file")
+	}
+	if !loaded.Matches("cats", "en", "0,5") {
+		t.Errorf("loaded checkpoint should match the original query")
+	}
+	if loaded.CurrentPage != 2 {
+		t.Errorf("CurrentPage = %d, want 2", loaded.CurrentPage)
+	}
+	if loaded.OutputPath != cp.OutputPath {
+		t.Errorf("OutputPath = %q, want %q", loaded.OutputPath, cp.OutputPath)
+	}
+	if loaded.MarkResultSeen("hash-a") {
+		t.Errorf("a hash marked seen before Save should still be seen after Load")
+	}
+}
+
+func TestLoadCheckpointMissingFile(t *testing.T) {
+	cp, err := LoadCheckpoint(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("LoadCheckpoint on a missing file should not error, got: %v", err)
+	}
+	if cp != nil {
+		t.Errorf("LoadCheckpoint on a missing file should return nil, got %+v", cp)
+	}
+}
+
+func TestResultHashStableAndFieldDriven(t *testing.T) {
+	record := map[string]string{"Link": "https://x/a", "Title": "A Paper", "Snippet": "..."}
+
+	h1 := resultHash(record, []string{"Link", "Title"})
+	h2 := resultHash(record, []string{"Link", "Title"})
+	if h1 != h2 {
+		t.Errorf("resultHash should be stable for the same record and fields")
+	}
+
+	h3 := resultHash(record, []string{"Snippet"})
+	if h1 == h3 {
+		t.Errorf("resultHash over different fields should (almost certainly) differ")
+	}
+}
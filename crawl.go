@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// link is a single crawl frontier entry: a URL to fetch, the depth at
+// which it was discovered relative to the seed URL (depth 0), and the
+// Scholar result page it belongs to (unchanged by citation-graph hops,
+// advanced only by following "Next").
+type link struct {
+	url   string
+	depth int
+	page  int
+}
+
+// linkFrontier is an unbounded FIFO queue of links. The crawl's own workers
+// are both its producers (enqueue is called synchronously from inside
+// OnHTML, on a worker goroutine) and its consumers (those same workers
+// range over Out), so a plain bounded channel can deadlock: once it fills,
+// every worker blocks trying to send into it and nothing is left to drain
+// it. linkFrontier decouples the two sides with an internal goroutine that
+// buffers in a slice, so a send into In never blocks on Out being read.
+type linkFrontier struct {
+	In  chan<- link
+	Out <-chan link
+}
+
+// newLinkFrontier starts the buffering goroutine and returns the frontier.
+// Closing In drains any buffered links through Out and then closes it.
+func newLinkFrontier() *linkFrontier {
+	in := make(chan link)
+	out := make(chan link)
+	go runLinkFrontier(in, out)
+	return &linkFrontier{In: in, Out: out}
+}
+
+func runLinkFrontier(in <-chan link, out chan<- link) {
+	defer close(out)
+	var buf []link
+	for {
+		if len(buf) == 0 {
+			v, ok := <-in
+			if !ok {
+				return
+			}
+			buf = append(buf, v)
+			continue
+		}
+		select {
+		case v, ok := <-in:
+			if !ok {
+				for _, v := range buf {
+					out <- v
+				}
+				return
+			}
+			buf = append(buf, v)
+		case out <- buf[0]:
+			buf = buf[1:]
+		}
+	}
+}
+
+// VisitedSet tracks which URLs have already been queued or fetched, keyed
+// by the sha1 of their canonical form, so the same result or citation page
+// reached via two different paths is only scraped once.
+type VisitedSet struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func NewVisitedSet() *VisitedSet {
+	return &VisitedSet{seen: make(map[string]bool)}
+}
+
+// MarkVisited records rawURL as seen and reports whether it was new; callers
+// should only enqueue the link when this returns true.
+func (v *VisitedSet) MarkVisited(rawURL string) bool {
+	key := canonicalHash(rawURL)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.seen[key] {
+		return false
+	}
+	v.seen[key] = true
+	return true
+}
+
+func canonicalHash(rawURL string) string {
+	canonical := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		u.Fragment = ""
+		canonical = u.String()
+	}
+	sum := sha1.Sum([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// CrawlOptions bounds the breadth-first crawl: how deep it may follow
+// discovered links (result pages and "Cited by" / "All versions" subpages),
+// how many "Next" pages of the search itself it may page through, how many
+// workers fetch concurrently, and which hosts a discovered link must
+// belong to in order to be followed at all.
+type CrawlOptions struct {
+	MaxDepth     int
+	MaxPages     int
+	Workers      int
+	AllowedHosts []string
+
+	// ProxyPool, if non-nil, is checked against MinHealthyProxies before
+	// each fetch; once the pool collapses below that threshold the crawl
+	// stops enqueuing new links and drains the ones already queued without
+	// fetching them, instead of continuing on a handful of dead proxies.
+	ProxyPool         *ProxyPool
+	MinHealthyProxies int
+}
+
+func (o CrawlOptions) hostAllowed(rawURL string) bool {
+	if len(o.AllowedHosts) == 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, suffix := range o.AllowedHosts {
+		suffix = strings.ToLower(suffix)
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLink turns a possibly-relative href found on a page into an
+// absolute URL against that page's address.
+func resolveLink(base *url.URL, href string) (string, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return "", false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	return base.ResolveReference(ref).String(), true
+}
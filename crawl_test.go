@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVisitedSetMarkVisited(t *testing.T) {
+	v := NewVisitedSet()
+
+	if !v.MarkVisited("https://scholar.google.com/a") {
+		t.Fatalf("first MarkVisited of a new URL should report true")
+	}
+	if v.MarkVisited("https://scholar.google.com/a") {
+		t.Fatalf("second MarkVisited of the same URL should report false")
+	}
+	if v.MarkVisited("https://scholar.google.com/a#cited-by") {
+		t.Fatalf("a URL differing only by fragment should be treated as the same page")
+	}
+	if !v.MarkVisited("https://scholar.google.com/b") {
+		t.Fatalf("MarkVisited of a different URL should report true")
+	}
+}
+
+func TestCrawlOptionsHostAllowed(t *testing.T) {
+	opts := CrawlOptions{AllowedHosts: []string{"scholar.google.com"}}
+
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{"https://scholar.google.com/scholar?q=x", true},
+		{"https://mail.scholar.google.com/x", true},
+		{"https://evil.com/scholar.google.com", false},
+		{"not a url", false},
+	}
+	for _, c := range cases {
+		if got := opts.hostAllowed(c.url); got != c.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", c.url, got, c.want)
+		}
+	}
+}
+
+func TestCrawlOptionsHostAllowedEmptyAllowsAll(t *testing.T) {
+	opts := CrawlOptions{}
+	if !opts.hostAllowed("https://anywhere.test/x") {
+		t.Errorf("an empty AllowedHosts should allow every host")
+	}
+}
+
+func TestLinkFrontierNeverBlocksSend(t *testing.T) {
+	f := newLinkFrontier()
+
+	// Send far more links than any reasonable channel buffer without ever
+	// draining Out, to pin down that In doesn't block its producer.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			f.In <- link{url: "https://scholar.google.com/x", depth: 0, page: i}
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("sending into linkFrontier.In blocked; it should buffer unboundedly")
+	}
+
+	close(f.In)
+	count := 0
+	for range f.Out {
+		count++
+	}
+	if count != 10000 {
+		t.Errorf("drained %d links from Out, want 10000", count)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	e := htmlElement(t, `<a href="/scholar?q=cats">cats</a>`, "a")
+
+	got, ok := resolveLink(e.Request.URL, e.Attr("href"))
+	if !ok {
+		t.Fatalf("resolveLink returned ok=false")
+	}
+	want := e.Request.URL.Scheme + "://" + e.Request.URL.Host + "/scholar?q=cats"
+	if got != want {
+		t.Errorf("resolveLink = %q, want %q", got, want)
+	}
+
+	if _, ok := resolveLink(e.Request.URL, "   "); ok {
+		t.Errorf("resolveLink should reject a blank href")
+	}
+}
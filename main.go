@@ -1,15 +1,16 @@
 package main
 
 import (
-	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocolly/colly/v2"
@@ -19,155 +20,426 @@ type Headers struct {
 	values []string
 }
 
-type Selectors struct {
-	body    string
-	title   string
-	snippet string
-	link    string
-	authors string
-	next    string
-}
-
 type ScraperService struct {
 	baseUrl      string
 	collectorUrl string
-	selectors    *Selectors
-	maxPages     int
+	ruleSet      *RuleSet
 	outputDir    string
 	headers      *Headers
 }
 
 func NewScraperService() *ScraperService {
+	return NewScraperServiceWithRules(defaultRuleSet())
+}
+
+// NewScraperServiceWithRules builds a ScraperService around a caller-supplied
+// RuleSet, e.g. one loaded from --rules, instead of the built-in Scholar
+// rules. The CSV header order is derived from the rule set's field order,
+// plus the scraper's own "Depth" column recording how far the crawl had to
+// follow links to reach that result.
+func NewScraperServiceWithRules(ruleSet *RuleSet) *ScraperService {
 	return &ScraperService{
 		baseUrl:      "https://scholar.google.com/scholar",
 		collectorUrl: "scholar.google.com",
-		selectors: &Selectors{
-			body:    ".gs_r",
-			title:   ".gs_rt",
-			snippet: ".gs_rs",
-			link:    ".gs_rt a",
-			authors: ".gs_a",
-			next:    "#gs_n td a",
-		},
-		maxPages:  100,
-		outputDir: "output",
+		ruleSet:      ruleSet,
+		outputDir:    "output",
 		headers: &Headers{
-			values: []string{
-				"Title",
-				"Snippet",
-				"Link",
-				"Authors",
-				"Date",
-				"DOI",
-				"Journal",
-				"Cited by",
-				"All versions",
-				"Page",
-			},
+			values: append(append([]string{}, ruleSet.FieldNames()...), "Depth"),
 		},
 	}
 }
 
-func (s *ScraperService) Scrape(c *colly.Collector, writer *csv.Writer, url string, term string, currentPage *int, maxPages int) {
-	var citations, totalCitations int
-	var lastProcessedPage int = -1
-
-	c.OnHTML(s.selectors.body, func(e *colly.HTMLElement) {
-		title := strings.TrimSpace(e.ChildText(".gs_rt"))
-		snippet := strings.TrimSpace(e.ChildText(".gs_rs"))
-		link := strings.TrimSpace(e.ChildAttr(".gs_rt a", "href"))
-		authors := strings.TrimSpace(e.ChildText(".gs_a"))
-		date := extractDate(authors)
-		doi := extractDOI(link)
-		journal := extractJournal(authors)
-		citedBy := extractCitedBy(e)
-		allVersions := extractAllVersions(e)
-
-		if title == "" && snippet == "" {
-			return
+const (
+	depthCtxKey = "depth"
+	pageCtxKey  = "page"
+)
+
+// Scrape crawls the citation graph rooted at seedURL breadth-first: a pool
+// of opts.Workers goroutines pulls links from a shared frontier channel,
+// fetches each with c, and feeds newly discovered result pages, "Next"
+// pages, and "Cited by" / "All versions" subpages back into the same
+// frontier until opts.MaxDepth is reached. Every link is resolved against
+// the page it was found on, filtered through opts.hostAllowed, and
+// deduplicated via a VisitedSet before it is queued, so the same page is
+// never fetched twice no matter how many paths lead to it.
+//
+// If checkpoint is non-nil, every result is deduped against its
+// already-seen-results so a resumed run doesn't rewrite rows a prior run
+// already wrote, and the checkpoint is advanced and saved once per page
+// (colly's OnScraped fires after that page's callbacks have all run).
+func (s *ScraperService) Scrape(c *colly.Collector, sink Sink, seedURL string, term string, opts CrawlOptions, checkpoint *Checkpoint) {
+	visited := NewVisitedSet()
+	frontier := newLinkFrontier()
+	var pending sync.WaitGroup
+	var total int64
+	var totalMu sync.Mutex
+	var aborted int32
+
+	poolHealthy := func() bool {
+		if opts.ProxyPool == nil || opts.MinHealthyProxies <= 0 {
+			return true
 		}
-		if strings.Contains(strings.ToLower(title), strings.ToLower(term)) ||
-			strings.Contains(strings.ToLower(snippet), strings.ToLower(term)) {
-			err := writer.Write([]string{title, snippet, link, authors, date, doi, journal, citedBy, allVersions, fmt.Sprintf("%d", *currentPage+1)})
-			if err != nil {
-				log.Printf("Failed to write CSV record: %v", err)
-			} else {
-				citations++
-				totalCitations++
+		if opts.ProxyPool.HealthyCount() < opts.MinHealthyProxies {
+			if atomic.CompareAndSwapInt32(&aborted, 0, 1) {
+				log.Printf("Aborting crawl: proxy pool collapsed below --min-healthy-proxies=%d", opts.MinHealthyProxies)
 			}
-			writer.Flush()
+			return false
 		}
-	})
+		return true
+	}
 
-	c.OnHTML(s.selectors.next, func(e *colly.HTMLElement) {
-		if strings.Contains(e.Text, "Next") && *currentPage < maxPages {
-			if lastProcessedPage != *currentPage {
-				log.Printf("Page %d scraped.", *currentPage+1)
-				lastProcessedPage = *currentPage
-			}
+	enqueue := func(rawURL string, depth, page int) {
+		if atomic.LoadInt32(&aborted) == 1 {
+			return
+		}
+		if depth > opts.MaxDepth || !opts.hostAllowed(rawURL) || !visited.MarkVisited(rawURL) {
+			return
+		}
+		pending.Add(1)
+		frontier.In <- link{url: rawURL, depth: depth, page: page}
+	}
+
+	matchFields := s.ruleSet.MatchFields()
+	dedupFields := s.ruleSet.DedupFields()
 
-			nextPage := e.Attr("href")
-			*currentPage++
-			log.Printf("Navigating to page %d...", *currentPage+1)
+	c.OnHTML(s.ruleSet.Container, func(e *colly.HTMLElement) {
+		depth, _ := e.Request.Ctx.GetAny(depthCtxKey).(int)
+		page, _ := e.Request.Ctx.GetAny(pageCtxKey).(int)
+
+		record, ok := s.ruleSet.Extract(e)
+		if !ok {
+			return
+		}
 
-			citations = 0
-			err := e.Request.Visit(nextPage)
-			if err != nil {
-				log.Printf("Error visiting next page: %v", err)
+		if !anyFieldNonEmpty(record, matchFields) {
+			return
+		}
+		if fieldsContainTerm(record, matchFields, term) {
+			record["Depth"] = fmt.Sprintf("%d", depth)
+
+			write := checkpoint == nil || checkpoint.MarkResultSeen(resultHash(record, dedupFields))
+			if write {
+				if err := sink.WriteRow(record); err != nil {
+					log.Printf("Failed to write record: %v", err)
+				} else {
+					totalMu.Lock()
+					total++
+					totalMu.Unlock()
+				}
 			}
 		}
+
+		e.ForEach(".gs_fl a", func(_ int, el *colly.HTMLElement) {
+			if !strings.Contains(el.Text, "Cited by") && !strings.Contains(el.Text, "All") {
+				return
+			}
+			if next, ok := resolveLink(e.Request.URL, el.Attr("href")); ok {
+				enqueue(next, depth+1, page)
+			}
+		})
+	})
+
+	c.OnHTML(s.ruleSet.Next, func(e *colly.HTMLElement) {
+		depth, _ := e.Request.Ctx.GetAny(depthCtxKey).(int)
+		page, _ := e.Request.Ctx.GetAny(pageCtxKey).(int)
+		if !strings.Contains(e.Text, "Next") {
+			return
+		}
+		if page+1 >= opts.MaxPages {
+			return
+		}
+		if next, ok := resolveLink(e.Request.URL, e.Attr("href")); ok {
+			enqueue(next, depth, page+1)
+		}
 	})
 
 	c.OnScraped(func(r *colly.Response) {
-		if lastProcessedPage != *currentPage {
-			lastProcessedPage = *currentPage
-			log.Printf("Found %d citations on page %d", totalCitations, *currentPage)
+		if checkpoint == nil {
+			return
+		}
+		page, _ := r.Ctx.GetAny(pageCtxKey).(int)
+		checkpoint.AdvancePage(page)
+		if err := checkpoint.Save(); err != nil {
+			log.Printf("Failed to save checkpoint: %v", err)
 		}
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
+		if errors.Is(err, ErrCaptchaDetected) {
+			log.Printf("Blocked by a CAPTCHA/unusual-traffic interstitial on %s", r.Request.URL)
+			return
+		}
 		log.Printf("Request failed on URL: %s, Error: %v", r.Request.URL, err)
 	})
 
-	err := c.Visit(url)
-	if err != nil {
-		log.Fatalf("Failed to start scraping: %v", err)
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var workersWG sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			for l := range frontier.Out {
+				if !poolHealthy() {
+					pending.Done()
+					continue
+				}
+				ctx := colly.NewContext()
+				ctx.Put(depthCtxKey, l.depth)
+				ctx.Put(pageCtxKey, l.page)
+				if err := c.Request("GET", l.url, nil, ctx, nil); err != nil {
+					log.Printf("Error visiting %s: %v", l.url, err)
+				}
+				pending.Done()
+			}
+		}()
+	}
+
+	startPage := 0
+	if checkpoint != nil {
+		startPage = checkpoint.CurrentPage
+	}
+	enqueue(seedURL, 0, startPage)
+
+	go func() {
+		pending.Wait()
+		close(frontier.In)
+	}()
+
+	// Async is off on c, so c.Request above blocks until that request's
+	// callbacks finish; c.Wait() alone tracks no in-flight request at the
+	// moment it's called (the seed may still be sitting in frontier,
+	// unfetched) and would return immediately. Waiting on the worker pool
+	// itself is the real join: workers only exit once frontier is closed
+	// and fully drained, i.e. once the crawl has nothing left to fetch.
+	workersWG.Wait()
+	log.Printf("Total results found: %d", total)
+}
+
+// anyFieldNonEmpty reports whether record holds a non-empty value for at
+// least one of fields; it's used to drop items a RuleSet matched the
+// container for but couldn't actually extract anything meaningful from.
+func anyFieldNonEmpty(record map[string]string, fields []string) bool {
+	for _, f := range fields {
+		if record[f] != "" {
+			return true
+		}
 	}
+	return false
+}
+
+// fieldsContainTerm reports whether any of fields' values in record
+// contain term, case-insensitively.
+func fieldsContainTerm(record map[string]string, fields []string, term string) bool {
+	term = strings.ToLower(term)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(record[f]), term) {
+			return true
+		}
+	}
+	return false
+}
 
-	c.Wait()
-	log.Printf("Total results found: %d", totalCitations)
+type flagValues struct {
+	term         string
+	lang         string
+	sdt          string
+	slow         bool
+	rules        string
+	maxDepth     int
+	maxPages     int
+	workers      int
+	allowedHosts string
+	outputFormat string
+	render       string
+	userDataDir  string
+	state        string
+
+	proxies           string
+	userAgents        string
+	minHealthyProxies int
+	proxyMaxFailures  int
+	proxyCooldown     time.Duration
 }
 
-func (s *ScraperService) flags() (string, string, string, bool) {
+func (s *ScraperService) flags() flagValues {
 	term := flag.String("query", "", "Search term for Google Scholar")
 	lang := flag.String("lang", "en", "Language (default en)")
 	sdt := flag.String("sdt", "0,5", "Scholar document type (0,5=All, 0,33=Articles, 1,5=Case law, 0=No patents, 2=Patents only)")
 	slow := flag.Bool("slow", false, "Enable 'slow mode', lower request rate for extra caution")
+	rules := flag.String("rules", "", "Path to a rule file or a directory of rule files (YAML/JSON) describing how to scrape a site; defaults to the built-in Scholar rules")
+	maxDepth := flag.Int("max-depth", 1, "Maximum depth to follow discovered links (result pages, 'Cited by', 'All versions') from the seed search")
+	maxPages := flag.Int("max-pages", 100, "Maximum number of 'Next' pages to follow for a single search before stopping pagination")
+	workers := flag.Int("workers", 4, "Number of concurrent workers fetching pages from the crawl frontier")
+	allowedHosts := flag.String("allowed-hosts", "scholar.google.com", "Comma-separated host suffixes that discovered links must match to be followed")
+	outputFormat := flag.String("output-format", "csv", "Output sink format: csv, json, ndjson or sqlite")
+	render := flag.String("render", "http", "Page fetch mode: http (plain colly fetch) or chromedp (headless-Chrome rendering with CAPTCHA detection)")
+	userDataDir := flag.String("user-data-dir", "", "Chrome user-data-dir to persist cookies across runs (only used with --render=chromedp)")
+	state := flag.String("state", "", "Path to a checkpoint file to resume from and flush progress to; defaults to output/state-<query>.json")
+	proxies := flag.String("proxies", "", "Path to a file of one proxy URL per line to rotate requests through; falls back to the comma-separated SCRAPER_PROXIES env var, or direct connections if neither is set")
+	userAgents := flag.String("user-agents", "", "Path to a file of one User-Agent string per line to rotate through; defaults to a small built-in list")
+	minHealthyProxies := flag.Int("min-healthy-proxies", 0, "Abort the crawl once fewer than this many proxies are healthy (0 disables the check)")
+	proxyMaxFailures := flag.Int("proxy-max-failures", 3, "Consecutive failures (errors, 429s, 403s) before a proxy is quarantined")
+	proxyCooldown := flag.Duration("proxy-cooldown", 5*time.Minute, "How long a quarantined proxy sits out before it's tried again")
 	flag.Parse()
 	if *term == "" {
 		log.Fatal("Error: Please provide a search term using -query flag followed by a search term (word)")
 	}
-	return *term, *lang, *sdt, *slow
+	return flagValues{
+		term:              *term,
+		lang:              *lang,
+		sdt:               *sdt,
+		slow:              *slow,
+		rules:             *rules,
+		maxDepth:          *maxDepth,
+		maxPages:          *maxPages,
+		workers:           *workers,
+		allowedHosts:      *allowedHosts,
+		outputFormat:      *outputFormat,
+		render:            *render,
+		userDataDir:       *userDataDir,
+		state:             *state,
+		proxies:           *proxies,
+		userAgents:        *userAgents,
+		minHealthyProxies: *minHealthyProxies,
+		proxyMaxFailures:  *proxyMaxFailures,
+		proxyCooldown:     *proxyCooldown,
+	}
 }
 
-func (s *ScraperService) createCollector(slow bool) *colly.Collector {
+// loadRuleSet resolves the --rules flag: empty keeps the built-in Scholar
+// rules, a file loads a single RuleSet, and a directory loads every rule
+// file in it and uses the first one (callers that need all of them, e.g. a
+// multi-site run, should call LoadRuleDir directly).
+func loadRuleSet(path string) *RuleSet {
+	if path == "" {
+		return defaultRuleSet()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Fatalf("Failed to stat --rules path %s: %v", path, err)
+	}
+
+	if !info.IsDir() {
+		rs, err := LoadRuleSet(path)
+		if err != nil {
+			log.Fatalf("Failed to load rule file: %v", err)
+		}
+		return rs
+	}
+
+	ruleSets, err := LoadRuleDir(path)
+	if err != nil {
+		log.Fatalf("Failed to load rule directory: %v", err)
+	}
+	if len(ruleSets) == 0 {
+		log.Fatalf("No rule files found in %s", path)
+	}
+	return ruleSets[0]
+}
+
+// CollectorConfig bundles everything createCollector needs to assemble a
+// Collector; it grew a field every time a request added another crawl-wide
+// concern (rendering, now proxies/UA rotation) and a plain parameter list
+// had stopped being readable.
+type CollectorConfig struct {
+	Slow        bool
+	Workers     int
+	Render      string
+	UserDataDir string
+
+	// ProxyPool, if non-nil, is installed as the Collector's transport so
+	// each request is routed through and attributed back to one of its
+	// proxies; it has no effect when Render is "chromedp" (see the warning
+	// logged in createCollector). User-Agent rotation is always installed;
+	// with a nil UserAgents it rotates through the default User-Agent list.
+	ProxyPool  *ProxyPool
+	UserAgents *UserAgentRotator
+}
+
+// loadUserAgents reads one User-Agent string per line from path, skipping
+// blank lines and "#" comments; an empty path leaves the rotator to fall
+// back to its built-in default list.
+func loadUserAgents(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read --user-agents file %s: %v", path, err)
+	}
+
+	var agents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		agents = append(agents, line)
+	}
+	return agents
+}
+
+// createCollector builds the Collector used by the crawl's worker pool.
+// It is intentionally synchronous (Async is left at its default off):
+// concurrency comes from the worker goroutines in Scrape, each driving the
+// same Collector, rather than from colly's own async queue, so a worker
+// only reports a link finished once its callbacks have actually run.
+//
+// When cfg.Render is "chromedp", the plain HTTP fetch is swapped for a
+// single shared headless-Chrome transport so JS-rendered pages and
+// interstitials are visible to the parser; the returned *ChromedpTransport
+// is nil otherwise, and non-nil only so the caller can Close it once the
+// crawl finishes.
+//
+// The Collector itself carries no domain allowlist: --allowed-hosts needs
+// to accept arbitrary suffixes (see CrawlOptions.hostAllowed), which colly's
+// own AllowedDomains can't express since it only matches a fixed set of
+// exact hosts. Host filtering is enforced earlier instead, in Scrape's
+// enqueue, before a link is ever turned into a request.
+func (s *ScraperService) createCollector(cfg CollectorConfig) (*colly.Collector, *ChromedpTransport) {
 	c := colly.NewCollector(
-		colly.AllowedDomains(s.collectorUrl),
-		colly.Async(true),
 		colly.MaxDepth(100),
 	)
-	delay := getBaseDelay(slow)
+	delay := getBaseDelay(cfg.Slow)
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*scholar.google.com*",
-		Parallelism: 1,
+		Parallelism: cfg.Workers,
 		Delay:       delay,
 	})
-	return c
+
+	if cfg.ProxyPool != nil {
+		if cfg.Render == "chromedp" {
+			// ChromedpTransport below replaces whatever transport we set
+			// here, so a pool installed via WithTransport would silently
+			// never see a request: Chrome does its own networking.
+			log.Printf("Warning: --proxies has no effect with --render=chromedp; Chrome does its own networking and never goes through the proxy pool")
+		} else {
+			c.WithTransport(cfg.ProxyPool.Transport())
+		}
+	}
+
+	uaRotator := cfg.UserAgents
+	if uaRotator == nil {
+		uaRotator = NewUserAgentRotator(nil)
+	}
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("User-Agent", uaRotator.Next())
+	})
+
+	var transport *ChromedpTransport
+	if cfg.Render == "chromedp" {
+		transport = NewChromedpTransport(cfg.UserDataDir, delay)
+		c.WithTransport(transport)
+	}
+	return c, transport
 }
 
-func (s *ScraperService) writeHeaders(writer *csv.Writer, headers *Headers) {
-	err := writer.Write(headers.values)
-	if err != nil {
+func (s *ScraperService) writeHeaders(sink Sink, headers *Headers) {
+	if err := sink.WriteHeader(headers.values); err != nil {
 		log.Fatalf("Failed to write headers to file: %v", err)
 	}
 }
@@ -176,7 +448,15 @@ func (s *ScraperService) constructURL(term string, page int, lang string, sdt st
 	return fmt.Sprintf("%s?start=%d&q=%s&hl=%s&as_sdt=%s", s.baseUrl, page*10, term, lang, sdt)
 }
 
-func (s *ScraperService) createOutputFile(term string) (*os.File, string) {
+// createSink resolves the output directory next to the executable and
+// builds the Sink matching --output-format. On a fresh run it names the
+// file after the search term, the current timestamp, and the format's own
+// file extension, and records that name on checkpoint so a later resume
+// of the same query can find it again; on resume it reopens
+// checkpoint.OutputPath in append mode instead, so the rows a prior run
+// already wrote (and the checkpoint is now skipping as duplicates) aren't
+// dropped onto the floor of a brand-new file.
+func (s *ScraperService) createSink(term string, format string, checkpoint *Checkpoint, resume bool) (Sink, string) {
 	path, err := os.Executable()
 	if err != nil {
 		log.Fatalf("Failed to get exe path: %v", err)
@@ -189,65 +469,32 @@ func (s *ScraperService) createOutputFile(term string) (*os.File, string) {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
-	filePath := s.buildAbsolutePath(term)
-	file, err := os.Create(filePath)
+	filePath := checkpoint.OutputPath
+	if !resume || filePath == "" {
+		filePath = s.buildAbsolutePath(term, format)
+		resume = false
+	}
+	checkpoint.SetOutputPath(filePath)
+
+	sink, err := NewSink(format, filePath, resume)
 	if err != nil {
-		log.Fatalf("Failed to create the output file: %v", err)
+		log.Fatalf("Failed to create the output sink: %v", err)
 	}
 
-	return file, filePath
+	return sink, filePath
 }
 
-func (s *ScraperService) buildAbsolutePath(term string) string {
+func (s *ScraperService) buildAbsolutePath(term string, format string) string {
 	stamp := time.Now().Format("20060102-150405")
-	fileName := fmt.Sprintf("scrape-%s-%s.csv", term, stamp)
+	fileName := fmt.Sprintf("scrape-%s-%s.%s", term, stamp, fileExtension(format))
 
 	return filepath.Join(s.outputDir, fileName)
 }
 
-func extractDate(authors string) string {
-	re := regexp.MustCompile(`(19|20)\d{2}`)
-	if match := re.FindString(authors); match != "" {
-		return match
-	}
-
-	parts := strings.Split(authors, "-")
-	if len(parts) > 1 {
-		lastPart := strings.TrimSpace(parts[len(parts)-1])
-		if match := re.FindString(lastPart); match != "" {
-			return match
-		}
-		return lastPart
-	}
-
-	return "Unknown"
-}
-
-func extractDOI(link string) string {
-	if strings.Contains(link, "doi.org") {
-		return link
-	}
-	return "N/A"
-}
-
-func extractJournal(authors string) string {
-	parts := strings.Split(authors, "-")
-	if len(parts) > 1 {
-		return strings.TrimSpace(parts[0])
-	}
-	return "Unknown"
-}
-
-func extractCitedBy(e *colly.HTMLElement) string {
-	citedBy := e.ChildText(".gs_fl a")
-	if strings.Contains(citedBy, "Cited by") {
-		re := regexp.MustCompile(`Cited by (\d+)`)
-		matches := re.FindStringSubmatch(citedBy)
-		if len(matches) > 1 {
-			return matches[1]
-		}
-	}
-	return "0"
+// defaultStatePath is the --state path used when none is given: one
+// checkpoint file per query, so rerunning the same search resumes it.
+func (s *ScraperService) defaultStatePath(term string) string {
+	return filepath.Join(s.outputDir, fmt.Sprintf("state-%s.json", term))
 }
 
 func getBaseDelay(slow bool) time.Duration {
@@ -259,34 +506,76 @@ func getBaseDelay(slow bool) time.Duration {
 	return baseDelay
 }
 
-func extractAllVersions(e *colly.HTMLElement) string {
-	allVersions := e.ChildText(".gs_fl a")
-	if strings.Contains(allVersions, "All") {
-		re := regexp.MustCompile(`All (\d+) versions`)
-		matches := re.FindStringSubmatch(allVersions)
-		if len(matches) > 1 {
-			return matches[1]
-		}
-	}
-	return "0"
-}
-
 func main() {
 	service := NewScraperService()
-	term, lang, sdt, slowMode := service.flags()
+	flags := service.flags()
+	if flags.rules != "" {
+		service = NewScraperServiceWithRules(loadRuleSet(flags.rules))
+	}
+
+	statePath := flags.state
+	if statePath == "" {
+		statePath = service.defaultStatePath(flags.term)
+	}
+	checkpoint, err := LoadCheckpoint(statePath)
+	if err != nil {
+		log.Printf("Failed to load checkpoint %s, starting fresh: %v", statePath, err)
+		checkpoint = nil
+	}
+	resuming := checkpoint != nil && checkpoint.Matches(flags.term, flags.lang, flags.sdt)
+	if resuming {
+		log.Printf("Resuming from checkpoint %s at page %d", statePath, checkpoint.CurrentPage)
+	} else {
+		checkpoint = NewCheckpoint(statePath, flags.term, flags.lang, flags.sdt)
+	}
 
-	page := 0
-	url := service.constructURL(term, page, lang, sdt)
-	log.Printf("Scraping URL: %s", url)
+	seedURL := service.constructURL(flags.term, checkpoint.CurrentPage, flags.lang, flags.sdt)
+	log.Printf("Scraping URL: %s", seedURL)
 
-	file, fileName := service.createOutputFile(term)
-	writer := csv.NewWriter(file)
-	defer file.Close()
+	sink, fileName := service.createSink(flags.term, flags.outputFormat, checkpoint, resuming)
+	defer func() {
+		if err := sink.Close(); err != nil {
+			log.Printf("Failed to close output sink: %v", err)
+		}
+	}()
 
-	service.writeHeaders(writer, service.headers)
-	collector := service.createCollector(slowMode)
+	service.writeHeaders(sink, service.headers)
 
-	service.Scrape(collector, writer, url, term, &page, service.maxPages)
+	rawProxies, err := LoadProxies(flags.proxies)
+	if err != nil {
+		log.Fatalf("Failed to load --proxies: %v", err)
+	}
+	var proxyPool *ProxyPool
+	if len(rawProxies) > 0 {
+		proxyPool, err = NewProxyPool(rawProxies, flags.proxyMaxFailures, flags.proxyCooldown)
+		if err != nil {
+			log.Fatalf("Failed to build proxy pool: %v", err)
+		}
+		log.Printf("Rotating across %d proxies", len(rawProxies))
+	}
+	uaRotator := NewUserAgentRotator(loadUserAgents(flags.userAgents))
+
+	collector, transport := service.createCollector(CollectorConfig{
+		Slow:        flags.slow,
+		Workers:     flags.workers,
+		Render:      flags.render,
+		UserDataDir: flags.userDataDir,
+		ProxyPool:   proxyPool,
+		UserAgents:  uaRotator,
+	})
+	if transport != nil {
+		defer transport.Close()
+	}
+
+	opts := CrawlOptions{
+		MaxDepth:          flags.maxDepth,
+		MaxPages:          flags.maxPages,
+		Workers:           flags.workers,
+		AllowedHosts:      strings.Split(flags.allowedHosts, ","),
+		ProxyPool:         proxyPool,
+		MinHealthyProxies: flags.minHealthyProxies,
+	}
+	service.Scrape(collector, sink, seedURL, flags.term, opts, checkpoint)
 
-	log.Printf("Scrape complete. It navigated through %d pages. The results were saved to a CSV file: %s\n", page, fileName)
+	log.Printf("Scrape complete. The results were saved to: %s\n", fileName)
 }
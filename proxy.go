@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultUserAgents is used when no --user-agents file is given.
+var defaultUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36",
+}
+
+// ProxyHealth is one proxy's recent track record: how many requests it has
+// served successfully or not, how many of those failures looked like a
+// block (429/403), and whether it is currently quarantined.
+type ProxyHealth struct {
+	mu                  sync.Mutex
+	successes           int
+	failures            int
+	blockedResponses    int
+	consecutiveFailures int
+	quarantinedUntil    time.Time
+}
+
+func (h *ProxyHealth) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.successes++
+	h.consecutiveFailures = 0
+}
+
+func (h *ProxyHealth) recordFailure(blocked bool) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures++
+	h.consecutiveFailures++
+	if blocked {
+		h.blockedResponses++
+	}
+	return h.consecutiveFailures
+}
+
+func (h *ProxyHealth) quarantine(cooldown time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.quarantinedUntil = time.Now().Add(cooldown)
+}
+
+func (h *ProxyHealth) healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Now().After(h.quarantinedUntil)
+}
+
+// ProxyPool rotates requests across a fixed list of proxies, temporarily
+// quarantining one after maxConsecutiveFailures in a row until cooldown
+// elapses, and re-introducing it automatically afterwards.
+type ProxyPool struct {
+	proxies                []*url.URL
+	health                 []*ProxyHealth
+	maxConsecutiveFailures int
+	cooldown               time.Duration
+	next                   uint64
+}
+
+// NewProxyPool parses rawProxies (http(s):// or socks5:// URLs) into a
+// pool that quarantines a proxy after maxConsecutiveFailures and brings it
+// back after cooldown.
+func NewProxyPool(rawProxies []string, maxConsecutiveFailures int, cooldown time.Duration) (*ProxyPool, error) {
+	pool := &ProxyPool{maxConsecutiveFailures: maxConsecutiveFailures, cooldown: cooldown}
+	for _, raw := range rawProxies {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+		}
+		pool.proxies = append(pool.proxies, u)
+		pool.health = append(pool.health, &ProxyHealth{})
+	}
+	return pool, nil
+}
+
+// pick returns the next proxy in rotation that isn't quarantined, falling
+// back to a plain round-robin pick if every proxy currently is.
+func (p *ProxyPool) pick() (int, *url.URL, bool) {
+	n := len(p.proxies)
+	if n == 0 {
+		return -1, nil, false
+	}
+	start := int(atomic.AddUint64(&p.next, 1) % uint64(n))
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if p.health[idx].healthy() {
+			return idx, p.proxies[idx], true
+		}
+	}
+	return start, p.proxies[start], true
+}
+
+// Transport adapts the pool to colly's WithTransport hook: each request is
+// dispatched through whichever proxy pick() picks for it, and the outcome
+// is recorded against that same proxy's health, all within a single
+// RoundTrip call. The proxy index never has to leave this struct (e.g. as
+// a custom request header), so nothing about it is visible to the target
+// site.
+func (p *ProxyPool) Transport() http.RoundTripper {
+	return &proxyTransport{pool: p}
+}
+
+// proxyTransport is an http.RoundTripper that round-robins requests across
+// a ProxyPool, lazily building and reusing one *http.Transport per proxy
+// so connections to a given proxy are pooled the way a plain
+// http.Transport would pool them.
+type proxyTransport struct {
+	pool *ProxyPool
+
+	mu         sync.Mutex
+	transports []*http.Transport
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idx, target, ok := t.pool.pick()
+	if !ok {
+		return nil, fmt.Errorf("proxy pool: no proxies configured")
+	}
+
+	resp, err := t.transportFor(idx, target).RoundTrip(req)
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	t.pool.recordResponse(idx, statusCode, err)
+	return resp, err
+}
+
+func (t *proxyTransport) transportFor(idx int, target *url.URL) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transports == nil {
+		t.transports = make([]*http.Transport, len(t.pool.proxies))
+	}
+	if t.transports[idx] == nil {
+		t.transports[idx] = &http.Transport{Proxy: http.ProxyURL(target)}
+	}
+	return t.transports[idx]
+}
+
+// recordResponse updates the health of the proxy at idx, the one
+// proxyTransport just routed a request through. statusCode is the
+// response status (0 if err is non-nil).
+func (p *ProxyPool) recordResponse(idx int, statusCode int, err error) {
+	if idx < 0 || idx >= len(p.health) {
+		return
+	}
+
+	blocked := statusCode == http.StatusTooManyRequests || statusCode == http.StatusForbidden
+	if err != nil || blocked {
+		if p.health[idx].recordFailure(blocked) >= p.maxConsecutiveFailures {
+			p.health[idx].quarantine(p.cooldown)
+		}
+		return
+	}
+	p.health[idx].recordSuccess()
+}
+
+// HealthyCount reports how many proxies in the pool are not currently
+// quarantined.
+func (p *ProxyPool) HealthyCount() int {
+	count := 0
+	for _, h := range p.health {
+		if h.healthy() {
+			count++
+		}
+	}
+	return count
+}
+
+// LoadProxies reads one proxy URL per line from path, skipping blank lines
+// and "#" comments. If path is empty, it falls back to the comma-separated
+// SCRAPER_PROXIES environment variable; with neither set, it returns no
+// proxies (direct connections only).
+func LoadProxies(path string) ([]string, error) {
+	if path == "" {
+		if env := os.Getenv("SCRAPER_PROXIES"); env != "" {
+			return splitAndTrim(env, ","), nil
+		}
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading proxies file %s: %w", path, err)
+	}
+
+	var proxies []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+	return proxies, nil
+}
+
+func splitAndTrim(s, sep string) []string {
+	parts := strings.Split(s, sep)
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// UserAgentRotator cycles through a fixed pool of User-Agent strings so
+// consecutive requests don't all present the same client fingerprint.
+type UserAgentRotator struct {
+	agents []string
+	next   uint64
+}
+
+// NewUserAgentRotator builds a rotator over agents, or defaultUserAgents
+// if agents is empty.
+func NewUserAgentRotator(agents []string) *UserAgentRotator {
+	if len(agents) == 0 {
+		agents = defaultUserAgents
+	}
+	return &UserAgentRotator{agents: agents}
+}
+
+// Next returns the next User-Agent string in rotation.
+func (u *UserAgentRotator) Next() string {
+	idx := atomic.AddUint64(&u.next, 1) % uint64(len(u.agents))
+	return u.agents[idx]
+}
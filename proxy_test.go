@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProxyHealthQuarantineAndCooldown(t *testing.T) {
+	h := &ProxyHealth{}
+	if !h.healthy() {
+		t.Fatalf("a fresh ProxyHealth should start out healthy")
+	}
+
+	h.quarantine(20 * time.Millisecond)
+	if h.healthy() {
+		t.Fatalf("ProxyHealth should be unhealthy immediately after quarantine")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !h.healthy() {
+		t.Fatalf("ProxyHealth should be healthy again once the cooldown elapses")
+	}
+}
+
+func TestProxyHealthRecordSuccessResetsFailures(t *testing.T) {
+	h := &ProxyHealth{}
+	h.recordFailure(false)
+	h.recordFailure(false)
+	h.recordSuccess()
+	if n := h.recordFailure(false); n != 1 {
+		t.Errorf("recordFailure after a recordSuccess = %d, want 1 (counter reset)", n)
+	}
+}
+
+func TestProxyPoolRecordResponseQuarantinesAfterMaxFailures(t *testing.T) {
+	pool, err := NewProxyPool([]string{"http://proxy-a.test", "http://proxy-b.test"}, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("NewProxyPool: %v", err)
+	}
+
+	pool.recordResponse(0, 0, errDummy)
+	if pool.HealthyCount() != 2 {
+		t.Fatalf("HealthyCount = %d after one failure, want 2 (below the quarantine threshold)", pool.HealthyCount())
+	}
+
+	pool.recordResponse(0, 0, errDummy)
+	if pool.HealthyCount() != 1 {
+		t.Errorf("HealthyCount = %d after reaching maxConsecutiveFailures, want 1", pool.HealthyCount())
+	}
+}
+
+func TestProxyPoolRecordResponseBlockedStatusQuarantines(t *testing.T) {
+	pool, err := NewProxyPool([]string{"http://proxy-a.test"}, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("NewProxyPool: %v", err)
+	}
+
+	pool.recordResponse(0, http.StatusTooManyRequests, nil)
+	if pool.HealthyCount() != 0 {
+		t.Errorf("a 429 response should count as a block and quarantine the proxy")
+	}
+}
+
+func TestProxyPoolRecordResponseIgnoresOutOfRangeIndex(t *testing.T) {
+	pool, err := NewProxyPool([]string{"http://proxy-a.test"}, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("NewProxyPool: %v", err)
+	}
+
+	pool.recordResponse(5, 0, errDummy)
+	if pool.HealthyCount() != 1 {
+		t.Errorf("an out-of-range index must not affect any proxy's health")
+	}
+}
+
+func TestProxyTransportRoutesThroughTargetAndRecordsOutcome(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer upstream.Close()
+
+	pool, err := NewProxyPool([]string{upstream.URL}, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("NewProxyPool: %v", err)
+	}
+
+	client := &http.Client{Transport: pool.Transport()}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.test/anything", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d from the proxy", resp.StatusCode, http.StatusForbidden)
+	}
+	if pool.HealthyCount() != 0 {
+		t.Errorf("a 403 routed through the only proxy should quarantine it, HealthyCount = %d", pool.HealthyCount())
+	}
+}
+
+func TestUserAgentRotatorCyclesThroughAll(t *testing.T) {
+	agents := []string{"ua-1", "ua-2", "ua-3"}
+	r := NewUserAgentRotator(agents)
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(agents)*2; i++ {
+		seen[r.Next()] = true
+	}
+	for _, a := range agents {
+		if !seen[a] {
+			t.Errorf("rotator never returned %q over %d calls", a, len(agents)*2)
+		}
+	}
+}
+
+func TestNewUserAgentRotatorDefaultsWhenEmpty(t *testing.T) {
+	r := NewUserAgentRotator(nil)
+	if r.Next() == "" {
+		t.Errorf("an empty agents list should fall back to defaultUserAgents, not an empty string")
+	}
+}
+
+// errDummy is a stand-in transport error for tests that only care that
+// recordResponse treats any non-nil error as a failure.
+var errDummy = &testError{"dummy transport error"}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrCaptchaDetected is returned by ChromedpTransport when a navigation
+// renders Google's "unusual traffic" / CAPTCHA interstitial instead of real
+// results, so callers can tell a block apart from an ordinary failure
+// instead of silently writing garbage rows for it.
+var ErrCaptchaDetected = errors.New("captcha or unusual-traffic interstitial detected")
+
+// captchaSelectors are checked against the rendered DOM after every
+// navigation to decide whether Scholar served an interstitial.
+var captchaSelectors = []string{
+	"#gs_captcha_f",
+	"form#captcha-form",
+	"div.g-recaptcha",
+}
+
+// ChromedpTransport is an http.RoundTripper that renders each request with
+// a single shared headless Chrome instance instead of performing a plain
+// HTTP fetch, so colly's usual OnHTML/OnResponse pipeline can parse
+// JavaScript-rendered pages unmodified. Construct one ChromedpTransport per
+// crawl and pass it to colly.WithTransport rather than creating a new
+// browser per request.
+type ChromedpTransport struct {
+	allocCtx   context.Context
+	cancelFunc context.CancelFunc
+	delay      time.Duration
+
+	mu sync.Mutex // serializes navigations against the one shared allocator
+}
+
+// NewChromedpTransport launches a single Chrome allocator, optionally
+// persisting its profile (cookies, local storage) to userDataDir so a
+// Scholar session survives across runs, and honors delay between
+// navigations the same way the plain HTTP path honors --slow.
+func NewChromedpTransport(userDataDir string, delay time.Duration) *ChromedpTransport {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.Flag("headless", true))
+	if userDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(userDataDir))
+	}
+
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	return &ChromedpTransport{allocCtx: allocCtx, cancelFunc: cancel, delay: delay}
+}
+
+// Close releases the underlying Chrome allocator; call it once the crawl
+// is done.
+func (t *ChromedpTransport) Close() {
+	t.cancelFunc()
+}
+
+func (t *ChromedpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.delay > 0 {
+		time.Sleep(t.delay)
+	}
+
+	ctx, cancel := chromedp.NewContext(t.allocCtx)
+	defer cancel()
+
+	var html string
+	var interstitial bool
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(req.URL.String()),
+		chromedp.Evaluate(captchaDetectionScript(), &interstitial),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("chromedp render of %s: %w", req.URL, err)
+	}
+	if interstitial {
+		return nil, fmt.Errorf("%s: %w", req.URL, ErrCaptchaDetected)
+	}
+
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader([]byte(html))),
+		Request:    req,
+	}
+	resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+	return resp, nil
+}
+
+// captchaDetectionScript builds a JS boolean expression that is true if any
+// captchaSelectors element is present in the rendered document.
+func captchaDetectionScript() string {
+	conditions := make([]string, len(captchaSelectors))
+	for i, sel := range captchaSelectors {
+		conditions[i] = fmt.Sprintf("document.querySelector(%q) !== null", sel)
+	}
+	return strings.Join(conditions, " || ")
+}
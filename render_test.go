@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestCaptchaDetectionScript(t *testing.T) {
+	got := captchaDetectionScript()
+
+	want := `document.querySelector("#gs_captcha_f") !== null || ` +
+		`document.querySelector("form#captcha-form") !== null || ` +
+		`document.querySelector("div.g-recaptcha") !== null`
+	if got != want {
+		t.Errorf("captchaDetectionScript() = %q, want %q", got, want)
+	}
+}
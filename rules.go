@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldRule describes how to pull a single named field out of a result item.
+// Selector is always evaluated relative to the RuleSet's Container. Mode
+// controls whether Selector's text or one of its attributes is read. Role
+// tags a field's purpose (one of the roleXxx constants below) so callers
+// that need "the title field" or "the link field" for a given site don't
+// have to hardcode its Name.
+type FieldRule struct {
+	Name     string `json:"name" yaml:"name"`
+	Selector string `json:"selector" yaml:"selector"`
+	Mode     string `json:"mode" yaml:"mode"` // "text" (default) or "attr"
+	Attr     string `json:"attr,omitempty" yaml:"attr,omitempty"`
+	Regex    string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Group    int    `json:"group,omitempty" yaml:"group,omitempty"`
+	Fallback string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	Required bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	Role     string `json:"role,omitempty" yaml:"role,omitempty"`
+
+	compiled *regexp.Regexp
+}
+
+// Field roles recognized by RuleSet.RoleField. A rule file can leave Role
+// unset on every field; MatchFields and DedupFields then fall back to
+// every field the rule set extracts.
+const (
+	roleTitle   = "title"
+	roleSnippet = "snippet"
+	roleLink    = "link"
+)
+
+// RuleSet is a single site's scraper definition: the container each result
+// item lives in, the pagination link, and the fields to pull from each item.
+type RuleSet struct {
+	Name      string      `json:"name" yaml:"name"`
+	Container string      `json:"container" yaml:"container"`
+	Next      string      `json:"next" yaml:"next"`
+	Fields    []FieldRule `json:"fields" yaml:"fields"`
+}
+
+// FieldNames returns the field names in rule order; this is what drives the
+// output header order so it always matches the active rule set.
+func (rs *RuleSet) FieldNames() []string {
+	names := make([]string, len(rs.Fields))
+	for i, f := range rs.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// RoleField returns the Name of the field tagged with the given role, or
+// "" if no field in this rule set declares it.
+func (rs *RuleSet) RoleField(role string) string {
+	for _, f := range rs.Fields {
+		if f.Role == role {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// MatchFields returns the field names whose values should be checked
+// against the --query search term: the fields tagged role: title and
+// role: snippet, or, if the rule set declares neither, every field it
+// extracts.
+func (rs *RuleSet) MatchFields() []string {
+	var fields []string
+	if t := rs.RoleField(roleTitle); t != "" {
+		fields = append(fields, t)
+	}
+	if s := rs.RoleField(roleSnippet); s != "" {
+		fields = append(fields, s)
+	}
+	if len(fields) == 0 {
+		return rs.FieldNames()
+	}
+	return fields
+}
+
+// DedupFields returns the field names that uniquely identify a scraped
+// result for checkpoint dedup: the fields tagged role: link and
+// role: title, or, if the rule set declares neither, every field it
+// extracts.
+func (rs *RuleSet) DedupFields() []string {
+	var fields []string
+	if l := rs.RoleField(roleLink); l != "" {
+		fields = append(fields, l)
+	}
+	if t := rs.RoleField(roleTitle); t != "" {
+		fields = append(fields, t)
+	}
+	if len(fields) == 0 {
+		return rs.FieldNames()
+	}
+	return fields
+}
+
+func (rs *RuleSet) compile() error {
+	for i := range rs.Fields {
+		f := &rs.Fields[i]
+		if f.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return fmt.Errorf("rule set %q: field %q: invalid regex %q: %w", rs.Name, f.Name, f.Regex, err)
+		}
+		f.compiled = re
+	}
+	return nil
+}
+
+// Extract applies every field rule to e and returns the resulting record.
+// If a required field's selector (and regex, if any) yields nothing, ok is
+// false and the item should be discarded; a required field's Fallback is
+// not used.
+func (rs *RuleSet) Extract(e *colly.HTMLElement) (record map[string]string, ok bool) {
+	record = make(map[string]string, len(rs.Fields))
+	for _, f := range rs.Fields {
+		value := f.extract(e)
+		if value == "" {
+			if f.Required {
+				return nil, false
+			}
+			value = f.Fallback
+		}
+		record[f.Name] = value
+	}
+	return record, true
+}
+
+func (f *FieldRule) extract(e *colly.HTMLElement) string {
+	var raw string
+	if f.Mode == "attr" {
+		raw = e.ChildAttr(f.Selector, f.Attr)
+	} else {
+		raw = e.ChildText(f.Selector)
+	}
+	raw = strings.TrimSpace(raw)
+
+	if f.compiled == nil {
+		return raw
+	}
+	matches := f.compiled.FindStringSubmatch(raw)
+	if matches == nil {
+		return ""
+	}
+	if f.Group > 0 && f.Group < len(matches) {
+		return matches[f.Group]
+	}
+	return matches[0]
+}
+
+// LoadRuleSet reads a single rule file, in YAML or JSON depending on its
+// extension.
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rule file %s: %w", path, err)
+	}
+
+	var rs RuleSet
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &rs)
+	case ".json":
+		err = json.Unmarshal(data, &rs)
+	default:
+		return nil, fmt.Errorf("rule file %s: unsupported extension %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing rule file %s: %w", path, err)
+	}
+	if rs.Name == "" {
+		rs.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := rs.compile(); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// LoadRuleDir reads every *.yaml, *.yml and *.json file in dir and returns
+// one RuleSet per file, sorted by file name so users can keep a per-site
+// scraper (Scholar, Semantic Scholar, PubMed, ...) in its own file without
+// recompiling.
+func LoadRuleDir(dir string) ([]*RuleSet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading rule directory %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+
+	ruleSets := make([]*RuleSet, 0, len(paths))
+	for _, p := range paths {
+		rs, err := LoadRuleSet(p)
+		if err != nil {
+			return nil, err
+		}
+		ruleSets = append(ruleSets, rs)
+	}
+	return ruleSets, nil
+}
+
+// defaultRuleSet reproduces the scraper's original hard-coded Google
+// Scholar selectors, so the tool still works with zero configuration when
+// no --rules path is given.
+func defaultRuleSet() *RuleSet {
+	rs := &RuleSet{
+		Name:      "scholar",
+		Container: ".gs_r",
+		Next:      "#gs_n td a",
+		Fields: []FieldRule{
+			{Name: "Title", Selector: ".gs_rt", Mode: "text", Role: roleTitle},
+			{Name: "Snippet", Selector: ".gs_rs", Mode: "text", Role: roleSnippet},
+			{Name: "Link", Selector: ".gs_rt a", Mode: "attr", Attr: "href", Role: roleLink},
+			{Name: "Authors", Selector: ".gs_a", Mode: "text"},
+			{Name: "Date", Selector: ".gs_a", Mode: "text", Regex: `(19|20)\d{2}`, Fallback: "Unknown"},
+			{Name: "DOI", Selector: ".gs_rt a", Mode: "attr", Attr: "href", Regex: `^.*doi\.org.*$`, Fallback: "N/A"},
+			{Name: "Journal", Selector: ".gs_a", Mode: "text", Regex: `^([^-]+)-`, Group: 1, Fallback: "Unknown"},
+			{Name: "Cited by", Selector: ".gs_fl a", Mode: "text", Regex: `Cited by (\d+)`, Group: 1, Fallback: "0"},
+			{Name: "All versions", Selector: ".gs_fl a", Mode: "text", Regex: `All (\d+) versions`, Group: 1, Fallback: "0"},
+		},
+	}
+	if err := rs.compile(); err != nil {
+		log.Fatalf("default rule set failed to compile: %v", err)
+	}
+	return rs
+}
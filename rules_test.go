@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// htmlElement serves html from a local test server and returns the
+// colly.HTMLElement for the first match of selector, for use as Extract's
+// input in tests.
+func htmlElement(t *testing.T, html, selector string) *colly.HTMLElement {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(html))
+	}))
+	t.Cleanup(srv.Close)
+
+	var el *colly.HTMLElement
+	c := colly.NewCollector()
+	c.OnHTML(selector, func(e *colly.HTMLElement) {
+		if el == nil {
+			el = e
+		}
+	})
+	if err := c.Visit(srv.URL); err != nil {
+		t.Fatalf("visiting test server: %v", err)
+	}
+	if el == nil {
+		t.Fatalf("selector %q matched nothing in %q", selector, html)
+	}
+	return el
+}
+
+func TestRuleSetExtract(t *testing.T) {
+	rs := &RuleSet{
+		Container: ".item",
+		Fields: []FieldRule{
+			{Name: "Title", Selector: ".t", Mode: "text", Role: roleTitle},
+			{Name: "Link", Selector: "a", Mode: "attr", Attr: "href", Role: roleLink},
+			{Name: "Year", Selector: ".meta", Mode: "text", Regex: `(19|20)\d{2}`, Fallback: "Unknown"},
+		},
+	}
+	if err := rs.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	html := `<div class="item"><span class="t">A Paper</span><a href="https://x.test/a">x</a><span class="meta">J. Foo - 2019</span></div>`
+	e := htmlElement(t, html, rs.Container)
+
+	record, ok := rs.Extract(e)
+	if !ok {
+		t.Fatalf("Extract returned ok=false")
+	}
+	if record["Title"] != "A Paper" {
+		t.Errorf("Title = %q, want %q", record["Title"], "A Paper")
+	}
+	if record["Link"] != "https://x.test/a" {
+		t.Errorf("Link = %q, want %q", record["Link"], "https://x.test/a")
+	}
+	if record["Year"] != "2019" {
+		t.Errorf("Year = %q, want %q", record["Year"], "2019")
+	}
+}
+
+func TestRuleSetExtractFallback(t *testing.T) {
+	rs := &RuleSet{
+		Container: ".item",
+		Fields: []FieldRule{
+			{Name: "Year", Selector: ".meta", Mode: "text", Regex: `(19|20)\d{2}`, Fallback: "Unknown"},
+		},
+	}
+	if err := rs.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	html := `<div class="item"><span class="meta">no year here</span></div>`
+	e := htmlElement(t, html, rs.Container)
+
+	record, ok := rs.Extract(e)
+	if !ok {
+		t.Fatalf("Extract returned ok=false")
+	}
+	if record["Year"] != "Unknown" {
+		t.Errorf("Year = %q, want fallback %q", record["Year"], "Unknown")
+	}
+}
+
+func TestRuleSetExtractRequiredMissing(t *testing.T) {
+	rs := &RuleSet{
+		Container: ".item",
+		Fields: []FieldRule{
+			{Name: "Title", Selector: ".t", Mode: "text", Required: true},
+		},
+	}
+	if err := rs.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	html := `<div class="item"><span class="other">nothing matches .t</span></div>`
+	e := htmlElement(t, html, rs.Container)
+
+	if _, ok := rs.Extract(e); ok {
+		t.Fatalf("Extract returned ok=true for a missing required field")
+	}
+}
+
+func TestRuleSetRoleField(t *testing.T) {
+	rs := &RuleSet{
+		Fields: []FieldRule{
+			{Name: "Title", Role: roleTitle},
+			{Name: "Snippet", Role: roleSnippet},
+			{Name: "Authors"},
+		},
+	}
+
+	if got := rs.RoleField(roleTitle); got != "Title" {
+		t.Errorf("RoleField(title) = %q, want %q", got, "Title")
+	}
+	if got := rs.RoleField(roleLink); got != "" {
+		t.Errorf("RoleField(link) = %q, want empty", got)
+	}
+}
+
+func TestRuleSetMatchAndDedupFieldsFallback(t *testing.T) {
+	// A rule set that declares no roles at all (e.g. a hand-written site
+	// config someone forgot to tag) must still produce usable fields
+	// instead of silently matching nothing.
+	rs := &RuleSet{
+		Fields: []FieldRule{
+			{Name: "Heading"},
+			{Name: "URL"},
+		},
+	}
+
+	match := rs.MatchFields()
+	if len(match) != 2 || match[0] != "Heading" || match[1] != "URL" {
+		t.Errorf("MatchFields() = %v, want every field as a fallback", match)
+	}
+
+	dedup := rs.DedupFields()
+	if len(dedup) != 2 || dedup[0] != "Heading" || dedup[1] != "URL" {
+		t.Errorf("DedupFields() = %v, want every field as a fallback", dedup)
+	}
+}
+
+func TestRuleSetMatchAndDedupFieldsFromRoles(t *testing.T) {
+	rs := &RuleSet{
+		Fields: []FieldRule{
+			{Name: "Heading", Role: roleTitle},
+			{Name: "Summary", Role: roleSnippet},
+			{Name: "URL", Role: roleLink},
+			{Name: "Authors"},
+		},
+	}
+
+	match := rs.MatchFields()
+	if len(match) != 2 || match[0] != "Heading" || match[1] != "Summary" {
+		t.Errorf("MatchFields() = %v, want [Heading Summary]", match)
+	}
+
+	dedup := rs.DedupFields()
+	if len(dedup) != 2 || dedup[0] != "URL" || dedup[1] != "Heading" {
+		t.Errorf("DedupFields() = %v, want [URL Heading]", dedup)
+	}
+}
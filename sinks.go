@@ -0,0 +1,362 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Sink is the output side of the scraper: the extraction loop in Scrape
+// writes one record per matched result through a Sink, without caring
+// whether it ends up as a CSV row, a JSON document, an NDJSON stream, or a
+// SQLite table.
+type Sink interface {
+	WriteHeader(fields []string) error
+	WriteRow(record map[string]string) error
+	Close() error
+}
+
+// NewSink builds the Sink named by format (csv, json, ndjson or sqlite) at
+// path. When resume is true, path is expected to be a prior run's own
+// output file (carried over via the checkpoint) and is reopened in append
+// mode instead of being truncated, so a restarted crawl adds to what it
+// already wrote rather than losing it; resume is ignored for sqlite, which
+// already appends rows to the same "results" table by construction.
+func NewSink(format string, path string, resume bool) (Sink, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		skipHeader := resume && fileHasContent(path)
+		file, err := openOutputFile(path, resume)
+		if err != nil {
+			return nil, fmt.Errorf("creating CSV output file: %w", err)
+		}
+		return NewCSVSink(file, skipHeader), nil
+	case "json":
+		sink, err := NewJSONSink(path, resume)
+		if err != nil {
+			return nil, fmt.Errorf("creating JSON output file: %w", err)
+		}
+		return sink, nil
+	case "ndjson":
+		file, err := openOutputFile(path, resume)
+		if err != nil {
+			return nil, fmt.Errorf("creating NDJSON output file: %w", err)
+		}
+		return NewNDJSONSink(file), nil
+	case "sqlite":
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			return nil, fmt.Errorf("opening SQLite output file: %w", err)
+		}
+		return NewSQLiteSink(db), nil
+	default:
+		return nil, fmt.Errorf("unknown --output-format %q (want csv, json, ndjson or sqlite)", format)
+	}
+}
+
+// openOutputFile opens path for writing: append mode (creating it if it
+// doesn't exist yet) when resume is true, truncating create otherwise.
+func openOutputFile(path string, resume bool) (*os.File, error) {
+	if resume {
+		return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	}
+	return os.Create(path)
+}
+
+// fileHasContent reports whether path already exists and is non-empty,
+// i.e. whether appending to it would be continuing a prior run's file
+// rather than starting a new one.
+func fileHasContent(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0
+}
+
+// fileExtension returns the file extension buildAbsolutePath should use for
+// a given --output-format.
+func fileExtension(format string) string {
+	switch strings.ToLower(format) {
+	case "json":
+		return "json"
+	case "ndjson":
+		return "ndjson"
+	case "sqlite":
+		return "db"
+	default:
+		return "csv"
+	}
+}
+
+// CSVSink writes one CSV row per record, in the field order it was handed
+// in WriteHeader, and owns the underlying file.
+type CSVSink struct {
+	mu         sync.Mutex
+	file       *os.File
+	writer     *csv.Writer
+	fields     []string
+	skipHeader bool
+}
+
+// NewCSVSink wraps file. skipHeader is true when file is a prior run's own
+// output reopened in append mode: WriteHeader still records the field
+// order, but doesn't write a second header row into the middle of the
+// file.
+func NewCSVSink(file *os.File, skipHeader bool) *CSVSink {
+	return &CSVSink{file: file, writer: csv.NewWriter(file), skipHeader: skipHeader}
+}
+
+func (s *CSVSink) WriteHeader(fields []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fields = fields
+	if s.skipHeader {
+		return nil
+	}
+	if err := s.writer.Write(fields); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+func (s *CSVSink) WriteRow(record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		row[i] = record[field]
+	}
+	if err := s.writer.Write(row); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+func (s *CSVSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return err
+	}
+	return s.file.Close()
+}
+
+// JSONSink collects every record and writes them out as a single JSON
+// array on Close, since a JSON array can't be closed until the last record
+// is known. path is left untouched until Close succeeds: Close writes the
+// array to a temp file in the same directory and renames it over path, so
+// a crash or kill before Close (the exact scenario a checkpoint resume is
+// meant to survive) leaves the prior run's output file intact instead of an empty
+// or partial one.
+type JSONSink struct {
+	mu      sync.Mutex
+	path    string
+	fields  []string
+	records []map[string]string
+}
+
+// NewJSONSink prepares path for a fresh JSON array, or, when resume is true
+// and path already holds one from a prior run, loads its records first so
+// Close rewrites the whole array with the old rows still in it.
+func NewJSONSink(path string, resume bool) (*JSONSink, error) {
+	var records []map[string]string
+	if resume {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			if err := json.Unmarshal(data, &records); err != nil {
+				return nil, fmt.Errorf("reading prior JSON output %s: %w", path, err)
+			}
+		}
+	}
+
+	return &JSONSink{path: path, records: records}, nil
+}
+
+func (s *JSONSink) WriteHeader(fields []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields = fields
+	return nil
+}
+
+func (s *JSONSink) WriteRow(record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make(map[string]string, len(s.fields))
+	for _, field := range s.fields {
+		row[field] = record[field]
+	}
+	s.records = append(s.records, row)
+	return nil
+}
+
+func (s *JSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.records); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// NDJSONSink streams one JSON object per line, which is friendlier than a
+// single array for downstream tooling that wants to process results as
+// they arrive rather than waiting for the whole file.
+type NDJSONSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+	fields []string
+}
+
+func NewNDJSONSink(file *os.File) *NDJSONSink {
+	return &NDJSONSink{file: file, enc: json.NewEncoder(file)}
+}
+
+func (s *NDJSONSink) WriteHeader(fields []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields = fields
+	return nil
+}
+
+func (s *NDJSONSink) WriteRow(record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make(map[string]string, len(s.fields))
+	for _, field := range s.fields {
+		row[field] = record[field]
+	}
+	return s.enc.Encode(row)
+}
+
+func (s *NDJSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// sqliteBatchSize caps how many rows SQLiteSink buffers before it commits
+// them in a single transaction.
+const sqliteBatchSize = 100
+
+// SQLiteSink creates a "results" table with one text column per field and
+// batches inserts into transactions rather than committing row by row.
+type SQLiteSink struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	fields []string
+	batch  []map[string]string
+}
+
+func NewSQLiteSink(db *sql.DB) *SQLiteSink {
+	return &SQLiteSink{db: db}
+}
+
+func (s *SQLiteSink) WriteHeader(fields []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fields = fields
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = fmt.Sprintf("%q TEXT", field)
+	}
+	ddl := fmt.Sprintf("CREATE TABLE IF NOT EXISTS results (%s)", strings.Join(columns, ", "))
+	_, err := s.db.Exec(ddl)
+	return err
+}
+
+func (s *SQLiteSink) WriteRow(record map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make(map[string]string, len(s.fields))
+	for _, field := range s.fields {
+		row[field] = record[field]
+	}
+	s.batch = append(s.batch, row)
+
+	if len(s.batch) >= sqliteBatchSize {
+		return s.flushLocked()
+	}
+	return nil
+}
+
+func (s *SQLiteSink) flushLocked() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning SQLite transaction: %w", err)
+	}
+
+	columns := make([]string, len(s.fields))
+	placeholders := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		columns[i] = fmt.Sprintf("%q", field)
+		placeholders[i] = "?"
+	}
+	insert := fmt.Sprintf("INSERT INTO results (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	stmt, err := tx.Prepare(insert)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("preparing SQLite insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range s.batch {
+		values := make([]interface{}, len(s.fields))
+		for i, field := range s.fields {
+			values[i] = row[field]
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("inserting SQLite row: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing SQLite transaction: %w", err)
+	}
+	s.batch = s.batch[:0]
+	return nil
+}
+
+func (s *SQLiteSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flushLocked(); err != nil {
+		return err
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSVSinkWriteAndResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+
+	sink, err := NewSink("csv", path, false)
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	if err := sink.WriteHeader([]string{"Title", "Link"}); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if err := sink.WriteRow(map[string]string{"Title": "A", "Link": "https://x/a"}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Resuming should append a second row, not duplicate the header.
+	sink, err = NewSink("csv", path, true)
+	if err != nil {
+		t.Fatalf("NewSink (resume): %v", err)
+	}
+	if err := sink.WriteHeader([]string{"Title", "Link"}); err != nil {
+		t.Fatalf("WriteHeader (resume): %v", err)
+	}
+	if err := sink.WriteRow(map[string]string{"Title": "B", "Link": "https://x/b"}); err != nil {
+		t.Fatalf("WriteRow (resume): %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close (resume): %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("reading csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows (incl. header), want 3: %v", len(rows), rows)
+	}
+	if rows[0][0] != "Title" {
+		t.Errorf("row 0 = %v, want the header row", rows[0])
+	}
+	if rows[1][0] != "A" || rows[2][0] != "B" {
+		t.Errorf("rows = %v, want A then B with no duplicated header", rows)
+	}
+}
+
+func TestJSONSinkResumePreservesPriorRecords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+
+	sink, err := NewJSONSink(path, false)
+	if err != nil {
+		t.Fatalf("NewJSONSink: %v", err)
+	}
+	sink.WriteHeader([]string{"Title"})
+	sink.WriteRow(map[string]string{"Title": "A"})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	sink, err = NewJSONSink(path, true)
+	if err != nil {
+		t.Fatalf("NewJSONSink (resume): %v", err)
+	}
+	sink.WriteHeader([]string{"Title"})
+	sink.WriteRow(map[string]string{"Title": "B"})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close (resume): %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	var records []map[string]string
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("unmarshalling: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2 (prior run's row preserved): %v", len(records), records)
+	}
+	if records[0]["Title"] != "A" || records[1]["Title"] != "B" {
+		t.Errorf("records = %v, want [{Title A} {Title B}]", records)
+	}
+}
+
+func TestSQLiteSinkBatching(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("opening sqlite db: %v", err)
+	}
+	sink := NewSQLiteSink(db)
+	if err := sink.WriteHeader([]string{"Title"}); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+
+	// Write fewer rows than sqliteBatchSize so nothing is flushed until Close.
+	if err := sink.WriteRow(map[string]string{"Title": "A"}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if len(sink.batch) != 1 {
+		t.Fatalf("batch has %d rows before the batch size is reached, want 1 buffered", len(sink.batch))
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Close also closes db, so reopen the file to inspect what was flushed.
+	db, err = sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("reopening sqlite db: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM results`).Scan(&count); err != nil {
+		t.Fatalf("counting rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("results has %d rows, want 1 flushed on Close", count)
+	}
+}
+
+func TestSQLiteSinkFlushesAtBatchSize(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "out.db"))
+	if err != nil {
+		t.Fatalf("opening sqlite db: %v", err)
+	}
+	sink := NewSQLiteSink(db)
+	if err := sink.WriteHeader([]string{"Title"}); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+
+	for i := 0; i < sqliteBatchSize; i++ {
+		if err := sink.WriteRow(map[string]string{"Title": "row"}); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if len(sink.batch) != 0 {
+		t.Fatalf("batch has %d rows after hitting sqliteBatchSize, want it flushed to 0", len(sink.batch))
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM results`).Scan(&count); err != nil {
+		t.Fatalf("counting rows: %v", err)
+	}
+	if count != sqliteBatchSize {
+		t.Errorf("results has %d rows, want %d flushed automatically", count, sqliteBatchSize)
+	}
+}